@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/yourname/api-gateway/internal/auth"
+	"github.com/yourname/api-gateway/internal/config"
+	"github.com/yourname/api-gateway/internal/database"
+	"github.com/yourname/api-gateway/internal/health"
+	"github.com/yourname/api-gateway/internal/keys"
+	"github.com/yourname/api-gateway/internal/middleware"
+	"github.com/yourname/api-gateway/internal/proxy"
+	"github.com/yourname/api-gateway/internal/store"
+)
+
+func main() {
+	cfg := config.Load()
+
+	db, err := database.Connect(cfg.DatabaseURL)
+	if err != nil {
+		log.Fatalf("failed to connect to database: %v", err)
+	}
+	defer db.Close()
+
+	st := store.New(db)
+
+	keySet, err := keys.Load(cfg.KeysDir)
+	if err != nil {
+		log.Fatalf("failed to load signing keys: %v", err)
+	}
+
+	ctx := context.Background()
+	oauthProviders, err := auth.BuildOAuthProviders(ctx, cfg)
+	if err != nil {
+		log.Fatalf("failed to configure oauth providers: %v", err)
+	}
+
+	authHandler := auth.NewHandler(st, keySet, []byte(cfg.OAuthStateSecret), cfg.TokenTTL, cfg.RefreshTTL)
+	for name, provider := range oauthProviders {
+		authHandler.RegisterOAuthProvider(name, provider)
+	}
+
+	router, err := proxy.NewRouter(cfg.RoutesConfig, proxy.Dependencies{
+		Store:        st,
+		KeySet:       keySet,
+		APIKeyHeader: cfg.APIKeyHeader,
+	})
+	if err != nil {
+		log.Fatalf("failed to load routes from %s: %v", cfg.RoutesConfig, err)
+	}
+
+	watchCtx, cancelWatch := context.WithCancel(context.Background())
+	defer cancelWatch()
+	router.Watch(watchCtx, 5*time.Second)
+
+	mux := http.NewServeMux()
+	mux.Handle("/healthz", health.Handler())
+
+	mux.Handle("/auth/login", authHandler.LoginHandler())
+	mux.Handle("/auth/refresh", authHandler.RefreshHandler())
+	mux.Handle("/auth/logout", authHandler.LogoutHandler())
+	mux.Handle("/auth/{provider}/login", authHandler.OAuthLoginHandler())
+	mux.Handle("/auth/{provider}/callback", authHandler.OAuthCallbackHandler())
+	mux.Handle("/.well-known/jwks.json", authHandler.JWKSHandler())
+
+	adminAuth := middleware.Auth(middleware.AuthConfig{
+		Store:        st,
+		KeySet:       keySet,
+		APIKeyHeader: cfg.APIKeyHeader,
+	})
+	mux.Handle("/admin/keys", adminAuth(middleware.RequireScope("admin:keys")(authHandler.AddKeyHandler())))
+	mux.Handle("/admin/keys/{kid}/retire", adminAuth(middleware.RequireScope("admin:keys")(authHandler.RetireKeyHandler())))
+	mux.Handle("/admin/routes", adminAuth(middleware.RequireScope("admin:routes")(router.RoutesHandler())))
+
+	mux.Handle("/", router)
+
+	handler := middleware.RequestID(middleware.Logger(mux))
+
+	addr := fmt.Sprintf(":%d", cfg.Port)
+	log.Printf("gateway listening on %s", addr)
+	if err := http.ListenAndServe(addr, handler); err != nil {
+		log.Fatalf("gateway server stopped: %v", err)
+	}
+}
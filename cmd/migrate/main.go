@@ -1,27 +1,29 @@
 package main
 
 import (
-	"fmt"
 	"log"
 	"os"
-	"path/filepath"
-	"sort"
+	"strconv"
 
 	"github.com/yourname/api-gateway/internal/config"
 	"github.com/yourname/api-gateway/internal/database"
 )
 
+func usage() {
+	log.Println("usage: migrate <up [N]|down [N]|goto <version>|status|force <version>>")
+	os.Exit(2)
+}
+
 func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
 	cfg := config.Load()
 
-	files, err := filepath.Glob("migrations/*.sql")
+	migrations, err := loadMigrations("migrations")
 	if err != nil {
-		log.Fatalf("failed to read migrations: %v", err)
-	}
-	sort.Strings(files)
-	if len(files) == 0 {
-		log.Println("no migrations to apply")
-		return
+		log.Fatalf("failed to load migrations: %v", err)
 	}
 
 	db, err := database.Connect(cfg.DatabaseURL)
@@ -30,16 +32,53 @@ func main() {
 	}
 	defer db.Close()
 
-	for _, file := range files {
-		sqlBytes, err := os.ReadFile(file)
-		if err != nil {
-			log.Fatalf("failed to read %s: %v", file, err)
+	m := NewMigrator(db, migrations)
+
+	switch cmd := os.Args[1]; cmd {
+	case "up":
+		steps := optionalInt(os.Args[2:], 0)
+		err = m.Up(steps)
+	case "down":
+		steps := optionalInt(os.Args[2:], 0)
+		err = m.Down(steps)
+	case "goto":
+		if len(os.Args) != 3 {
+			usage()
+		}
+		var target int
+		target, err = strconv.Atoi(os.Args[2])
+		if err == nil {
+			err = m.Goto(target)
+		}
+	case "status":
+		err = m.Status()
+	case "force":
+		if len(os.Args) != 3 {
+			usage()
 		}
-		if _, err := db.Exec(string(sqlBytes)); err != nil {
-			log.Fatalf("failed to apply %s: %v", file, err)
+		var version int
+		version, err = strconv.Atoi(os.Args[2])
+		if err == nil {
+			err = m.Force(version)
 		}
-		log.Printf("applied %s", filepath.Base(file))
+	default:
+		usage()
 	}
 
-	fmt.Printf("applied %d migration(s)\n", len(files))
+	if err != nil {
+		log.Fatalf("migrate %s: %v", os.Args[1], err)
+	}
+}
+
+// optionalInt parses args[0] as an int if present, returning fallback
+// otherwise. Used for the optional N in "up [N]" / "down [N]".
+func optionalInt(args []string, fallback int) int {
+	if len(args) == 0 {
+		return fallback
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		usage()
+	}
+	return n
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// Migration is one versioned schema change, backed by a pair of SQL files:
+// NNNN_name.up.sql and NNNN_name.down.sql.
+type Migration struct {
+	Version      int
+	Name         string
+	UpSQL        string
+	DownSQL      string
+	UpChecksum   string
+	DownChecksum string
+}
+
+var migrationFileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// loadMigrations reads every NNNN_name.up.sql/NNNN_name.down.sql pair from
+// dir and returns them sorted by version. It's an error for an up file to
+// be missing its down counterpart, or vice versa.
+func loadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations dir: %w", err)
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		match := migrationFileRe.FindStringSubmatch(entry.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.Atoi(match[1])
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: invalid version: %w", entry.Name(), err)
+		}
+
+		raw, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("read %s: %w", entry.Name(), err)
+		}
+		checksum := sha256sum(raw)
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = m
+		}
+
+		switch match[3] {
+		case "up":
+			m.UpSQL = string(raw)
+			m.UpChecksum = checksum
+		case "down":
+			m.DownSQL = string(raw)
+			m.DownChecksum = checksum
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.UpSQL == "" {
+			return nil, fmt.Errorf("migration %04d_%s: missing .up.sql", m.Version, m.Name)
+		}
+		if m.DownSQL == "" {
+			return nil, fmt.Errorf("migration %04d_%s: missing .down.sql", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func sha256sum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func writeMigrationFile(t *testing.T, dir, name, contents string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(contents), 0o644); err != nil {
+		t.Fatalf("write %s: %v", name, err)
+	}
+}
+
+func TestLoadMigrationsOrdersByVersionAndChecksums(t *testing.T) {
+	dir := t.TempDir()
+	writeMigrationFile(t, dir, "0002_add_col.up.sql", "ALTER TABLE t ADD COLUMN c int;")
+	writeMigrationFile(t, dir, "0002_add_col.down.sql", "ALTER TABLE t DROP COLUMN c;")
+	writeMigrationFile(t, dir, "0001_init.up.sql", "CREATE TABLE t (id int);")
+	writeMigrationFile(t, dir, "0001_init.down.sql", "DROP TABLE t;")
+
+	migrations, err := loadMigrations(dir)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	if len(migrations) != 2 {
+		t.Fatalf("expected 2 migrations, got %d", len(migrations))
+	}
+	if migrations[0].Version != 1 || migrations[1].Version != 2 {
+		t.Fatalf("expected migrations sorted by version, got %+v", migrations)
+	}
+	if migrations[0].UpChecksum == "" || migrations[0].UpChecksum == migrations[1].UpChecksum {
+		t.Fatalf("expected distinct, populated checksums, got %+v", migrations)
+	}
+}
+
+func TestLoadMigrationsMissingDownFile(t *testing.T) {
+	dir := t.TempDir()
+	writeMigrationFile(t, dir, "0001_init.up.sql", "CREATE TABLE t (id int);")
+
+	if _, err := loadMigrations(dir); err == nil {
+		t.Fatal("expected an error for an up file with no matching down file")
+	}
+}
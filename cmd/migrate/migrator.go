@@ -0,0 +1,426 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+)
+
+// advisoryLockKey is an arbitrary constant used with pg_advisory_lock so
+// only one migrate process touches schema_migrations at a time, even
+// across hosts.
+const advisoryLockKey = 0x6761746577617932 // "gateway2", just a fixed value
+
+// Migrator applies and rolls back Migrations against db, tracking applied
+// versions (and whether the last change to one left it dirty) in
+// schema_migrations.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, migrations []Migration) *Migrator {
+	return &Migrator{db: db, migrations: migrations}
+}
+
+// ErrDirty is returned when schema_migrations reports the current version
+// as dirty, meaning a previous migration failed partway through and needs
+// manual intervention (see the force subcommand) before anything else runs.
+var ErrDirty = errors.New("database is in a dirty state; inspect it and run 'force <version>' once fixed")
+
+func (m *Migrator) ensureSchema() error {
+	_, err := m.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version    bigint PRIMARY KEY,
+			name       text NOT NULL,
+			checksum   text NOT NULL,
+			dirty      boolean NOT NULL DEFAULT false,
+			applied_at timestamptz NOT NULL DEFAULT now()
+		)
+	`)
+	return err
+}
+
+// lock acquires the session-level advisory lock on a single pinned
+// connection and returns a func to release it. pg_advisory_lock/unlock are
+// tied to the backend that took them, so the lock and unlock must share one
+// *sql.Conn rather than going through the pool, or the unlock can land on a
+// different connection and silently no-op.
+func (m *Migrator) lock() (func(), error) {
+	ctx := context.Background()
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire migration lock: %w", err)
+	}
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, advisoryLockKey); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("acquire migration lock: %w", err)
+	}
+	return func() {
+		if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, advisoryLockKey); err != nil {
+			log.Printf("migrate: failed to release advisory lock: %v", err)
+		}
+		conn.Close()
+	}, nil
+}
+
+// appliedVersions returns every applied version in schema_migrations,
+// keyed by version, along with whether it's currently marked dirty.
+func (m *Migrator) appliedVersions() (map[int]bool, error) {
+	rows, err := m.db.Query(`SELECT version, dirty FROM schema_migrations`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	applied := make(map[int]bool)
+	for rows.Next() {
+		var version int
+		var dirty bool
+		if err := rows.Scan(&version, &dirty); err != nil {
+			return nil, err
+		}
+		applied[version] = dirty
+	}
+	return applied, rows.Err()
+}
+
+// checkDirty fails fast if any applied version is marked dirty, since
+// running further migrations on top of an inconsistent schema would just
+// compound the problem.
+func (m *Migrator) checkDirty(applied map[int]bool) error {
+	for version, dirty := range applied {
+		if dirty {
+			return fmt.Errorf("version %d: %w", version, ErrDirty)
+		}
+	}
+	return nil
+}
+
+// verifyChecksums fails if a migration file that's already been applied no
+// longer matches the checksum recorded when it ran, catching edits to
+// historical migrations.
+func (m *Migrator) verifyChecksums() error {
+	rows, err := m.db.Query(`SELECT version, checksum FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	recorded := make(map[int]string)
+	for rows.Next() {
+		var version int
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return err
+		}
+		recorded[version] = checksum
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	return checksumMismatch(recorded, m.migrations)
+}
+
+// checksumMismatch reports the first migration in migrations whose recorded
+// checksum (if any) no longer matches its current file checksum. Split out
+// from verifyChecksums so the comparison itself can be unit tested without a
+// database.
+func checksumMismatch(recorded map[int]string, migrations []Migration) error {
+	for _, mig := range migrations {
+		if checksum, ok := recorded[mig.Version]; ok && checksum != mig.UpChecksum {
+			return fmt.Errorf("migration %04d_%s has changed since it was applied", mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// Up applies the next `steps` pending migrations in order, or every
+// pending migration when steps is 0.
+func (m *Migrator) Up(steps int) error {
+	if err := m.ensureSchema(); err != nil {
+		return err
+	}
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return err
+	}
+	if err := m.checkDirty(applied); err != nil {
+		return err
+	}
+	if err := m.verifyChecksums(); err != nil {
+		return err
+	}
+
+	applyCount := 0
+	for _, mig := range m.migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if steps > 0 && applyCount >= steps {
+			break
+		}
+		if err := m.applyUp(mig); err != nil {
+			return err
+		}
+		applyCount++
+	}
+
+	if applyCount == 0 {
+		log.Println("no migrations to apply")
+	}
+	return nil
+}
+
+// Down rolls back the most recently applied `steps` migrations, or every
+// applied migration when steps is 0.
+func (m *Migrator) Down(steps int) error {
+	if err := m.ensureSchema(); err != nil {
+		return err
+	}
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return err
+	}
+	if err := m.checkDirty(applied); err != nil {
+		return err
+	}
+
+	byVersion := make(map[int]Migration, len(m.migrations))
+	for _, mig := range m.migrations {
+		byVersion[mig.Version] = mig
+	}
+
+	rolledBack := 0
+	for i := len(m.migrations) - 1; i >= 0; i-- {
+		mig := m.migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if steps > 0 && rolledBack >= steps {
+			break
+		}
+		if err := m.applyDown(mig); err != nil {
+			return err
+		}
+		rolledBack++
+	}
+
+	if rolledBack == 0 {
+		log.Println("no migrations to roll back")
+	}
+	return nil
+}
+
+// Goto brings the schema to exactly target, applying or rolling back
+// whichever migrations are needed to get there.
+func (m *Migrator) Goto(target int) error {
+	if err := m.ensureSchema(); err != nil {
+		return err
+	}
+	applied, err := m.appliedVersions()
+	if err != nil {
+		return err
+	}
+	if err := m.checkDirty(applied); err != nil {
+		return err
+	}
+
+	current := 0
+	for version := range applied {
+		if version > current {
+			current = version
+		}
+	}
+
+	if target > current {
+		steps := 0
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; !ok && mig.Version <= target {
+				steps++
+			}
+		}
+		if steps == 0 {
+			log.Printf("no migrations to apply up to version %d", target)
+			return nil
+		}
+		return m.Up(steps)
+	}
+	if target < current {
+		steps := 0
+		for _, mig := range m.migrations {
+			if _, ok := applied[mig.Version]; ok && mig.Version > target && mig.Version <= current {
+				steps++
+			}
+		}
+		return m.Down(steps)
+	}
+	log.Printf("already at version %d", target)
+	return nil
+}
+
+// Status prints every migration's applied state, and flags any whose file
+// checksum no longer matches what was recorded when it ran.
+func (m *Migrator) Status() error {
+	if err := m.ensureSchema(); err != nil {
+		return err
+	}
+
+	recorded := make(map[int]struct {
+		checksum string
+		dirty    bool
+	})
+	rows, err := m.db.Query(`SELECT version, checksum, dirty FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var version int
+		var checksum string
+		var dirty bool
+		if err := rows.Scan(&version, &checksum, &dirty); err != nil {
+			rows.Close()
+			return err
+		}
+		recorded[version] = struct {
+			checksum string
+			dirty    bool
+		}{checksum, dirty}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return err
+	}
+	rows.Close()
+
+	for _, mig := range m.migrations {
+		state, ok := recorded[mig.Version]
+		switch {
+		case !ok:
+			fmt.Printf("%04d_%s  pending\n", mig.Version, mig.Name)
+		case state.dirty:
+			fmt.Printf("%04d_%s  applied (DIRTY)\n", mig.Version, mig.Name)
+		case state.checksum != mig.UpChecksum:
+			fmt.Printf("%04d_%s  applied (checksum mismatch, file has changed)\n", mig.Version, mig.Name)
+		default:
+			fmt.Printf("%04d_%s  applied\n", mig.Version, mig.Name)
+		}
+	}
+	return nil
+}
+
+// Force clears any dirty flag and makes schema_migrations reflect version
+// as the current state without running any SQL. Use it after manually
+// fixing a database left dirty by a failed migration.
+func (m *Migrator) Force(version int) error {
+	if err := m.ensureSchema(); err != nil {
+		return err
+	}
+
+	if _, err := m.db.Exec(`DELETE FROM schema_migrations WHERE version > $1`, version); err != nil {
+		return err
+	}
+
+	if version == 0 {
+		return nil
+	}
+
+	mig, ok := m.findMigration(version)
+	if !ok {
+		return fmt.Errorf("no migration with version %d", version)
+	}
+
+	_, err := m.db.Exec(`
+		INSERT INTO schema_migrations (version, name, checksum, dirty)
+		VALUES ($1, $2, $3, false)
+		ON CONFLICT (version) DO UPDATE SET checksum = $3, dirty = false
+	`, mig.Version, mig.Name, mig.UpChecksum)
+	return err
+}
+
+func (m *Migrator) findMigration(version int) (Migration, bool) {
+	for _, mig := range m.migrations {
+		if mig.Version == version {
+			return mig, true
+		}
+	}
+	return Migration{}, false
+}
+
+func (m *Migrator) applyUp(mig Migration) error {
+	// Mark dirty in its own, already-committed statement before opening the
+	// transaction that runs UpSQL. If that transaction fails and rolls back,
+	// the dirty row must still be there afterward, or a failed migration
+	// leaves no trace and gets silently retried next run. Mirrors applyDown.
+	if _, err := m.db.Exec(`
+		INSERT INTO schema_migrations (version, name, checksum, dirty)
+		VALUES ($1, $2, $3, true)
+	`, mig.Version, mig.Name, mig.UpChecksum); err != nil {
+		return fmt.Errorf("mark %04d_%s dirty: %w", mig.Version, mig.Name, err)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(mig.UpSQL); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("apply %04d_%s: %w (database left dirty, run 'force' once fixed)", mig.Version, mig.Name, err)
+	}
+
+	if _, err := tx.Exec(`UPDATE schema_migrations SET dirty = false WHERE version = $1`, mig.Version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("clear dirty flag for %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+
+	log.Printf("applied %04d_%s", mig.Version, mig.Name)
+	return nil
+}
+
+func (m *Migrator) applyDown(mig Migration) error {
+	if _, err := m.db.Exec(`UPDATE schema_migrations SET dirty = true WHERE version = $1`, mig.Version); err != nil {
+		return fmt.Errorf("mark %04d_%s dirty: %w", mig.Version, mig.Name, err)
+	}
+
+	tx, err := m.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(mig.DownSQL); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("roll back %04d_%s: %w (database left dirty, run 'force' once fixed)", mig.Version, mig.Name, err)
+	}
+
+	if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, mig.Version); err != nil {
+		tx.Rollback()
+		return fmt.Errorf("remove record of %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit rollback of %04d_%s: %w", mig.Version, mig.Name, err)
+	}
+
+	log.Printf("rolled back %04d_%s", mig.Version, mig.Name)
+	return nil
+}
@@ -0,0 +1,34 @@
+package main
+
+import "testing"
+
+func TestCheckDirty(t *testing.T) {
+	if err := (&Migrator{}).checkDirty(map[int]bool{1: false, 2: false}); err != nil {
+		t.Fatalf("expected no error for clean versions, got %v", err)
+	}
+
+	err := (&Migrator{}).checkDirty(map[int]bool{1: false, 2: true})
+	if err == nil {
+		t.Fatal("expected an error when a version is dirty")
+	}
+}
+
+func TestChecksumMismatch(t *testing.T) {
+	migrations := []Migration{
+		{Version: 1, Name: "init", UpChecksum: "abc"},
+		{Version: 2, Name: "add_col", UpChecksum: "def"},
+	}
+
+	if err := checksumMismatch(map[int]string{1: "abc", 2: "def"}, migrations); err != nil {
+		t.Fatalf("expected no error when checksums match, got %v", err)
+	}
+
+	// A version with no recorded checksum (not yet applied) is not a mismatch.
+	if err := checksumMismatch(map[int]string{1: "abc"}, migrations); err != nil {
+		t.Fatalf("expected no error for an unapplied version, got %v", err)
+	}
+
+	if err := checksumMismatch(map[int]string{1: "abc", 2: "changed"}, migrations); err == nil {
+		t.Fatal("expected an error when a recorded checksum no longer matches the file")
+	}
+}
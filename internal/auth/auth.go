@@ -1,44 +1,80 @@
 package auth
 
 import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
+	"strings"
 	"time"
 
 	jose "github.com/go-jose/go-jose/v4"
 	"github.com/go-jose/go-jose/v4/jwt"
 	"github.com/google/uuid"
 
+	"github.com/yourname/api-gateway/internal/keys"
 	"github.com/yourname/api-gateway/internal/store"
 )
 
+// KeySet is implemented by anything that can resolve a JWT "kid" header to
+// the Ed25519 public key that verifies it. *keys.KeySet satisfies this,
+// letting external services validate gateway-issued tokens against the
+// same key material without depending on the gateway's signing half.
+type KeySet interface {
+	PublicKey(kid string) (ed25519.PublicKey, bool)
+}
+
 // Handler deals with authentication endpoints such as /auth/login.
 type Handler struct {
-	store    *store.Store
-	secret   []byte
-	tokenTTL time.Duration
-	issuer   string
+	store          *store.Store
+	loginProvider  LoginProvider
+	oauthProviders map[string]OAuthProvider
+	keys           *keys.KeySet
+	stateSecret    []byte
+	tokenTTL       time.Duration
+	refreshTTL     time.Duration
+	issuer         string
 }
 
-// NewHandler builds a login handler.
-func NewHandler(st *store.Store, secret []byte, ttl time.Duration) *Handler {
+// NewHandler builds a login handler. Password login goes through st
+// directly, which satisfies LoginProvider; use RegisterOAuthProvider to add
+// external identity providers. Tokens are signed with ks's current key;
+// stateSecret only protects the OAuth2 CSRF state parameter.
+func NewHandler(st *store.Store, ks *keys.KeySet, stateSecret []byte, ttl, refreshTTL time.Duration) *Handler {
 	return &Handler{
-		store:    st,
-		secret:   secret,
-		tokenTTL: ttl,
-		issuer:   "api-gateway",
+		store:          st,
+		loginProvider:  st,
+		oauthProviders: make(map[string]OAuthProvider),
+		keys:           ks,
+		stateSecret:    stateSecret,
+		tokenTTL:       ttl,
+		refreshTTL:     refreshTTL,
+		issuer:         "api-gateway",
 	}
 }
 
+// RegisterOAuthProvider makes an external identity provider available at
+// /auth/{name}/login and /auth/{name}/callback.
+func (h *Handler) RegisterOAuthProvider(name string, provider OAuthProvider) {
+	h.oauthProviders[name] = provider
+}
+
 type loginRequest struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
 }
 
 type loginResponse struct {
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
+	Token            string    `json:"token"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshToken     string    `json:"refresh_token"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
 }
 
 // LoginHandler returns an http.Handler for POST /auth/login.
@@ -60,7 +96,7 @@ func (h *Handler) LoginHandler() http.Handler {
 			return
 		}
 
-		identity, err := h.store.AuthenticateUser(r.Context(), req.Email, req.Password)
+		identity, err := h.loginProvider.AuthenticateUser(r.Context(), req.Email, req.Password)
 		if err != nil {
 			if errors.Is(err, store.ErrInvalidCredentials) {
 				http.Error(w, "invalid credentials", http.StatusUnauthorized)
@@ -70,23 +106,20 @@ func (h *Handler) LoginHandler() http.Handler {
 			return
 		}
 
-		token, exp, err := h.generateToken(identity)
+		resp, err := h.issueTokens(r.Context(), identity, nil)
 		if err != nil {
 			http.Error(w, "could not issue token", http.StatusInternalServerError)
 			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(loginResponse{
-			Token:     token,
-			ExpiresAt: exp,
-		})
+		_ = json.NewEncoder(w).Encode(resp)
 	})
 }
 
 func (h *Handler) generateToken(identity *store.Identity) (string, time.Time, error) {
 	exp := time.Now().Add(h.tokenTTL)
-	signer, err := jose.NewSigner(jose.SigningKey{Algorithm: jose.HS256, Key: h.secret}, nil)
+	signer, err := h.keys.Signer()
 	if err != nil {
 		return "", time.Time{}, err
 	}
@@ -100,12 +133,14 @@ func (h *Handler) generateToken(identity *store.Identity) (string, time.Time, er
 	}
 
 	type customClaims struct {
-		Email string `json:"email"`
+		Email string   `json:"email"`
+		Scope []string `json:"scope"`
 		jwt.Claims
 	}
 
 	tokenBuilder := jwt.Signed(signer).Claims(customClaims{
 		Email:  identity.Email,
+		Scope:  identity.Scopes,
 		Claims: cl,
 	})
 	out, err := tokenBuilder.Serialize()
@@ -117,19 +152,30 @@ func (h *Handler) generateToken(identity *store.Identity) (string, time.Time, er
 }
 
 // VerifyToken parses and validates the JWT returning the associated identity.
-func VerifyToken(token string, secret []byte) (*Identity, error) {
-	parsed, err := jwt.ParseSigned(token, nil)
+// The token's "kid" header selects which of ks's public keys verifies it,
+// so rotating the signing key doesn't invalidate tokens already issued.
+func VerifyToken(token string, ks KeySet) (*Identity, error) {
+	parsed, err := jwt.ParseSigned(token, []jose.SignatureAlgorithm{jose.EdDSA})
 	if err != nil {
 		return nil, err
 	}
+	if len(parsed.Headers) == 0 || parsed.Headers[0].KeyID == "" {
+		return nil, errors.New("auth: token is missing a kid header")
+	}
+
+	pub, ok := ks.PublicKey(parsed.Headers[0].KeyID)
+	if !ok {
+		return nil, fmt.Errorf("auth: unknown signing key %q", parsed.Headers[0].KeyID)
+	}
 
 	type customClaims struct {
-		Email string `json:"email"`
+		Email string   `json:"email"`
+		Scope []string `json:"scope"`
 		jwt.Claims
 	}
 
 	var claims customClaims
-	if err := parsed.Claims(secret, &claims); err != nil {
+	if err := parsed.Claims(pub, &claims); err != nil {
 		return nil, err
 	}
 
@@ -148,6 +194,7 @@ func VerifyToken(token string, secret []byte) (*Identity, error) {
 		UserID: userID,
 		Email:  claims.Email,
 		Method: "jwt",
+		Scopes: claims.Scope,
 	}, nil
 }
 
@@ -157,5 +204,127 @@ func IdentityFromStore(identity *store.Identity, method string) Identity {
 		UserID: identity.UserID,
 		Email:  identity.Email,
 		Method: method,
+		Scopes: identity.Scopes,
+	}
+}
+
+// OAuthLoginHandler returns an http.Handler for GET /auth/{provider}/login,
+// which redirects the caller to the upstream provider's consent screen.
+func (h *Handler) OAuthLoginHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider, ok := h.providerByName(r.PathValue("provider"))
+		if !ok {
+			http.Error(w, "unknown provider", http.StatusNotFound)
+			return
+		}
+
+		state, err := h.signOAuthState()
+		if err != nil {
+			http.Error(w, "could not start login", http.StatusInternalServerError)
+			return
+		}
+
+		http.Redirect(w, r, provider.AuthCodeURL(state), http.StatusFound)
+	})
+}
+
+// OAuthCallbackHandler returns an http.Handler for GET /auth/{provider}/callback,
+// which exchanges the authorization code, resolves or provisions the local
+// identity, and returns the same token payload as LoginHandler.
+func (h *Handler) OAuthCallbackHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		provider, ok := h.providerByName(r.PathValue("provider"))
+		if !ok {
+			http.Error(w, "unknown provider", http.StatusNotFound)
+			return
+		}
+
+		if !h.verifyOAuthState(r.URL.Query().Get("state")) {
+			http.Error(w, "invalid or expired state", http.StatusBadRequest)
+			return
+		}
+
+		code := r.URL.Query().Get("code")
+		if code == "" {
+			http.Error(w, "missing code", http.StatusBadRequest)
+			return
+		}
+
+		token, err := provider.Exchange(r.Context(), code)
+		if err != nil {
+			http.Error(w, "could not exchange code", http.StatusBadGateway)
+			return
+		}
+
+		info, err := provider.UserInfo(r.Context(), token)
+		if err != nil {
+			http.Error(w, "could not fetch user info", http.StatusBadGateway)
+			return
+		}
+
+		identity, err := h.store.FindOrCreateOAuthIdentity(r.Context(), provider.Name(), info.Subject, info.Email, info.EmailVerified)
+		if err != nil {
+			if errors.Is(err, store.ErrEmailNotVerified) {
+				http.Error(w, "an account with this email already exists; verify your email with this provider or log in with a password first", http.StatusConflict)
+				return
+			}
+			http.Error(w, "could not provision user", http.StatusInternalServerError)
+			return
+		}
+
+		resp, err := h.issueTokens(r.Context(), identity, nil)
+		if err != nil {
+			http.Error(w, "could not issue token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+func (h *Handler) providerByName(name string) (OAuthProvider, bool) {
+	provider, ok := h.oauthProviders[name]
+	return provider, ok
+}
+
+// signOAuthState produces a short-lived, tamper-evident state value so the
+// callback can be verified without server-side session storage: a random
+// nonce and expiry, HMAC-signed with the handler's OAuth state secret.
+func (h *Handler) signOAuthState() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
 	}
+
+	expiry := time.Now().Add(10 * time.Minute).Unix()
+	payload := fmt.Sprintf("%s.%d", base64.RawURLEncoding.EncodeToString(nonce), expiry)
+
+	mac := hmac.New(sha256.New, h.stateSecret)
+	mac.Write([]byte(payload))
+	sig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+
+	return payload + "." + sig, nil
+}
+
+func (h *Handler) verifyOAuthState(state string) bool {
+	parts := strings.Split(state, ".")
+	if len(parts) != 3 {
+		return false
+	}
+
+	payload := parts[0] + "." + parts[1]
+	mac := hmac.New(sha256.New, h.stateSecret)
+	mac.Write([]byte(payload))
+	expectedSig := base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+	if !hmac.Equal([]byte(parts[2]), []byte(expectedSig)) {
+		return false
+	}
+
+	expiry, err := strconv.ParseInt(parts[1], 10, 64)
+	if err != nil {
+		return false
+	}
+
+	return time.Now().Unix() <= expiry
 }
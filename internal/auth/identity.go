@@ -4,6 +4,8 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+
+	"github.com/yourname/api-gateway/internal/scope"
 )
 
 // Identity captures the authenticated user details.
@@ -11,6 +13,7 @@ type Identity struct {
 	UserID uuid.UUID
 	Email  string
 	Method string
+	Scopes []string
 }
 
 type contextKey string
@@ -31,3 +34,13 @@ func FromContext(ctx context.Context) (Identity, bool) {
 	id, ok := val.(Identity)
 	return id, ok
 }
+
+// HasScope reports whether the identity stored in ctx, if any, has a scope
+// granting required (e.g. "read:service-a").
+func HasScope(ctx context.Context, required string) bool {
+	id, ok := FromContext(ctx)
+	if !ok {
+		return false
+	}
+	return scope.Has(id.Scopes, required)
+}
@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// JWKSHandler returns an http.Handler for GET /.well-known/jwks.json,
+// publishing every key (current and retired) so relying parties can
+// verify gateway-issued tokens without sharing a secret.
+func (h *Handler) JWKSHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(h.keys.JWKS())
+	})
+}
+
+type addKeyResponse struct {
+	KID string `json:"kid"`
+}
+
+// AddKeyHandler returns an http.Handler for POST /admin/keys, which
+// generates a new signing key and makes it current. Callers should mount
+// it behind middleware.RequireScope("admin:keys").
+func (h *Handler) AddKeyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		kid, err := h.keys.AddKey()
+		if err != nil {
+			http.Error(w, "could not add key", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(addKeyResponse{KID: kid})
+	})
+}
+
+// RetireKeyHandler returns an http.Handler for POST /admin/keys/{kid}/retire,
+// which marks a key as no longer used for signing (it remains valid for
+// verification). Callers should mount it behind
+// middleware.RequireScope("admin:keys").
+func (h *Handler) RetireKeyHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		kid := r.PathValue("kid")
+		if err := h.keys.RetireKey(kid); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
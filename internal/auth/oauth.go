@@ -0,0 +1,293 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"golang.org/x/oauth2"
+
+	"github.com/yourname/api-gateway/internal/config"
+)
+
+// Endpoints holds the OAuth2/OIDC URLs a provider needs.
+type Endpoints struct {
+	AuthURL     string
+	TokenURL    string
+	UserInfoURL string
+	// EmailsURL is an optional second endpoint listing the user's emails
+	// with per-address verification status, for providers (GitHub) whose
+	// primary userinfo endpoint doesn't expose verification at all. Left
+	// empty, EmailVerified is read from UserInfoURL's response as usual.
+	EmailsURL string
+}
+
+// UserInfoFields names the JSON fields to read off a provider's userinfo
+// response; providers disagree on naming (OIDC uses "sub", GitHub uses "id").
+// EmailVerified may be left empty for a provider whose userinfo response
+// never asserts it (e.g. GitHub's /user endpoint), in which case it's
+// treated as unverified.
+type UserInfoFields struct {
+	Subject       string
+	Email         string
+	EmailVerified string
+	Name          string
+}
+
+var defaultUserInfoFields = UserInfoFields{Subject: "sub", Email: "email", EmailVerified: "email_verified", Name: "name"}
+
+// genericProvider implements OAuthProvider for any standard OAuth2/OIDC
+// endpoint set. Keycloak, Google and ad-hoc OIDC issuers all use it;
+// provider-specific quirks (like GitHub's field names) are expressed via
+// UserInfoFields rather than a bespoke type per provider.
+type genericProvider struct {
+	name        string
+	oauth2      oauth2.Config
+	userInfoURL string
+	emailsURL   string
+	fields      UserInfoFields
+	httpClient  *http.Client
+}
+
+// NewGenericProvider builds an OAuthProvider from explicit endpoints. Use
+// DiscoverEndpoints to populate Endpoints from an issuer's discovery
+// document when the provider supports it.
+func NewGenericProvider(name string, cfg config.OAuthProviderConfig, endpoints Endpoints, scopes []string, fields UserInfoFields) OAuthProvider {
+	if fields == (UserInfoFields{}) {
+		fields = defaultUserInfoFields
+	}
+	return &genericProvider{
+		name: name,
+		oauth2: oauth2.Config{
+			ClientID:     cfg.ClientID,
+			ClientSecret: cfg.ClientSecret,
+			RedirectURL:  cfg.RedirectURL,
+			Scopes:       scopes,
+			Endpoint: oauth2.Endpoint{
+				AuthURL:  endpoints.AuthURL,
+				TokenURL: endpoints.TokenURL,
+			},
+		},
+		userInfoURL: endpoints.UserInfoURL,
+		emailsURL:   endpoints.EmailsURL,
+		fields:      fields,
+		httpClient:  http.DefaultClient,
+	}
+}
+
+func (p *genericProvider) Name() string { return p.name }
+
+func (p *genericProvider) AuthCodeURL(state string) string {
+	return p.oauth2.AuthCodeURL(state)
+}
+
+func (p *genericProvider) Exchange(ctx context.Context, code string) (*OAuthToken, error) {
+	token, err := p.oauth2.Exchange(ctx, code)
+	if err != nil {
+		return nil, fmt.Errorf("%s: exchange code: %w", p.name, err)
+	}
+	return &OAuthToken{
+		AccessToken:  token.AccessToken,
+		RefreshToken: token.RefreshToken,
+		Expiry:       token.Expiry,
+	}, nil
+}
+
+// bearerGet issues an authenticated GET against url using token, returning
+// the response for the caller to decode. Shared by UserInfo and
+// fetchVerifiedPrimaryEmail, which otherwise differ only in what they parse
+// out of the body.
+func (p *genericProvider) bearerGet(ctx context.Context, url string, token *OAuthToken) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token.AccessToken)
+	req.Header.Set("Accept", "application/json")
+	return p.httpClient.Do(req)
+}
+
+func (p *genericProvider) UserInfo(ctx context.Context, token *OAuthToken) (*OAuthUserInfo, error) {
+	resp, err := p.bearerGet(ctx, p.userInfoURL, token)
+	if err != nil {
+		return nil, fmt.Errorf("%s: fetch userinfo: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: userinfo returned status %d", p.name, resp.StatusCode)
+	}
+
+	var raw map[string]any
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return nil, fmt.Errorf("%s: decode userinfo: %w", p.name, err)
+	}
+
+	subject := stringField(raw, p.fields.Subject)
+	if subject == "" {
+		return nil, fmt.Errorf("%s: userinfo response missing %q", p.name, p.fields.Subject)
+	}
+
+	email := stringField(raw, p.fields.Email)
+	verified := boolField(raw, p.fields.EmailVerified)
+	if p.emailsURL != "" {
+		if e, v, ok := p.fetchVerifiedPrimaryEmail(ctx, token); ok {
+			email, verified = e, v
+		} else {
+			verified = false
+			log.Printf("%s: could not read verified primary email from %s, treating email as unverified", p.name, p.emailsURL)
+		}
+	}
+
+	return &OAuthUserInfo{
+		Subject:       subject,
+		Email:         email,
+		EmailVerified: verified,
+		Name:          stringField(raw, p.fields.Name),
+	}, nil
+}
+
+// fetchVerifiedPrimaryEmail calls emailsURL (GitHub's /user/emails) and
+// returns the account's primary email along with whether GitHub has it
+// marked verified. ok is false if the endpoint couldn't be read, in which
+// case the caller keeps userinfo's email and treats it as unverified.
+func (p *genericProvider) fetchVerifiedPrimaryEmail(ctx context.Context, token *OAuthToken) (email string, verified bool, ok bool) {
+	resp, err := p.bearerGet(ctx, p.emailsURL, token)
+	if err != nil {
+		return "", false, false
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", false, false
+	}
+
+	var emails []struct {
+		Email    string `json:"email"`
+		Primary  bool   `json:"primary"`
+		Verified bool   `json:"verified"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&emails); err != nil {
+		return "", false, false
+	}
+
+	for _, e := range emails {
+		if e.Primary {
+			return e.Email, e.Verified, true
+		}
+	}
+	return "", false, false
+}
+
+// boolField reads a field that may be a JSON bool or string ("true"/"false",
+// as some providers encode it) and returns it, defaulting to false when key
+// is empty or the field is absent.
+func boolField(raw map[string]any, key string) bool {
+	if key == "" {
+		return false
+	}
+	switch v := raw[key].(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	default:
+		return false
+	}
+}
+
+// stringField reads a field that may be a JSON string or number (GitHub's
+// "id" is numeric) and returns its string form.
+func stringField(raw map[string]any, key string) string {
+	v, ok := raw[key]
+	if !ok || v == nil {
+		return ""
+	}
+	if s, ok := v.(string); ok {
+		return strings.TrimSpace(s)
+	}
+	if f, ok := v.(float64); ok {
+		return strconv.FormatFloat(f, 'f', -1, 64)
+	}
+	return fmt.Sprintf("%v", v)
+}
+
+// discoveryDocument is the subset of a standard OIDC discovery document the
+// gateway needs.
+type discoveryDocument struct {
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	UserinfoEndpoint      string `json:"userinfo_endpoint"`
+}
+
+// DiscoverEndpoints fetches issuerURL + "/.well-known/openid-configuration"
+// and extracts the endpoints genericProvider needs. Keycloak, Google and
+// generic OIDC issuers all support this; providers that don't (GitHub)
+// should build Endpoints by hand instead.
+func DiscoverEndpoints(ctx context.Context, issuerURL string) (Endpoints, error) {
+	wellKnown := strings.TrimRight(issuerURL, "/") + "/.well-known/openid-configuration"
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, wellKnown, nil)
+	if err != nil {
+		return Endpoints{}, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return Endpoints{}, fmt.Errorf("fetch discovery document: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return Endpoints{}, fmt.Errorf("discovery document at %s returned status %d", wellKnown, resp.StatusCode)
+	}
+
+	var doc discoveryDocument
+	if err := json.NewDecoder(resp.Body).Decode(&doc); err != nil {
+		return Endpoints{}, fmt.Errorf("decode discovery document: %w", err)
+	}
+
+	return Endpoints{
+		AuthURL:     doc.AuthorizationEndpoint,
+		TokenURL:    doc.TokenEndpoint,
+		UserInfoURL: doc.UserinfoEndpoint,
+	}, nil
+}
+
+// githubEndpoints are GitHub's fixed OAuth2 endpoints; GitHub does not
+// implement OIDC discovery. EmailsURL is set because /user itself never
+// reports whether the account's email is verified - only /user/emails does.
+var githubEndpoints = Endpoints{
+	AuthURL:     "https://github.com/login/oauth/authorize",
+	TokenURL:    "https://github.com/login/oauth/access_token",
+	UserInfoURL: "https://api.github.com/user",
+	EmailsURL:   "https://api.github.com/user/emails",
+}
+
+var githubFields = UserInfoFields{Subject: "id", Email: "email", Name: "name"}
+
+// BuildOAuthProviders constructs an OAuthProvider for every configured
+// provider in cfg.OAuthProviders, resolving endpoints via discovery except
+// for providers (GitHub) known not to support it.
+func BuildOAuthProviders(ctx context.Context, cfg *config.Config) (map[string]OAuthProvider, error) {
+	providers := make(map[string]OAuthProvider, len(cfg.OAuthProviders))
+
+	for name, pcfg := range cfg.OAuthProviders {
+		switch name {
+		case "github":
+			providers[name] = NewGenericProvider(name, pcfg, githubEndpoints, []string{"read:user", "user:email"}, githubFields)
+		default:
+			endpoints, err := DiscoverEndpoints(ctx, pcfg.IssuerURL)
+			if err != nil {
+				return nil, fmt.Errorf("discover endpoints for provider %q: %w", name, err)
+			}
+			providers[name] = NewGenericProvider(name, pcfg, endpoints, []string{"openid", "email", "profile"}, defaultUserInfoFields)
+		}
+	}
+
+	return providers, nil
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yourname/api-gateway/internal/config"
+)
+
+func TestUserInfoGitHubUsesVerifiedPrimaryEmailFromEmailsEndpoint(t *testing.T) {
+	userSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 42, "email": "spoofed@example.com", "name": "Alice"}`))
+	}))
+	defer userSrv.Close()
+
+	emailsSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[
+			{"email": "secondary@example.com", "primary": false, "verified": true},
+			{"email": "real@example.com", "primary": true, "verified": true}
+		]`))
+	}))
+	defer emailsSrv.Close()
+
+	provider := NewGenericProvider("github", config.OAuthProviderConfig{}, Endpoints{
+		UserInfoURL: userSrv.URL,
+		EmailsURL:   emailsSrv.URL,
+	}, nil, githubFields)
+
+	info, err := provider.UserInfo(context.Background(), &OAuthToken{AccessToken: "tok"})
+	if err != nil {
+		t.Fatalf("UserInfo: %v", err)
+	}
+	if info.Email != "real@example.com" {
+		t.Errorf("expected the primary email from /user/emails to win, got %q", info.Email)
+	}
+	if !info.EmailVerified {
+		t.Error("expected EmailVerified to be true for a GitHub-verified primary email")
+	}
+}
+
+func TestUserInfoGitHubUnverifiedPrimaryEmail(t *testing.T) {
+	userSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 42, "email": "real@example.com", "name": "Alice"}`))
+	}))
+	defer userSrv.Close()
+
+	emailsSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`[{"email": "real@example.com", "primary": true, "verified": false}]`))
+	}))
+	defer emailsSrv.Close()
+
+	provider := NewGenericProvider("github", config.OAuthProviderConfig{}, Endpoints{
+		UserInfoURL: userSrv.URL,
+		EmailsURL:   emailsSrv.URL,
+	}, nil, githubFields)
+
+	info, err := provider.UserInfo(context.Background(), &OAuthToken{AccessToken: "tok"})
+	if err != nil {
+		t.Fatalf("UserInfo: %v", err)
+	}
+	if info.EmailVerified {
+		t.Error("expected EmailVerified to be false when GitHub reports the primary email as unverified")
+	}
+}
+
+func TestUserInfoGitHubEmailsEndpointUnreachableFailsClosed(t *testing.T) {
+	userSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id": 42, "email": "real@example.com", "name": "Alice"}`))
+	}))
+	defer userSrv.Close()
+
+	emailsSrv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer emailsSrv.Close()
+
+	provider := NewGenericProvider("github", config.OAuthProviderConfig{}, Endpoints{
+		UserInfoURL: userSrv.URL,
+		EmailsURL:   emailsSrv.URL,
+	}, nil, githubFields)
+
+	info, err := provider.UserInfo(context.Background(), &OAuthToken{AccessToken: "tok"})
+	if err != nil {
+		t.Fatalf("UserInfo: %v", err)
+	}
+	if info.EmailVerified {
+		t.Error("expected a failed /user/emails lookup to fail closed (unverified), not verified")
+	}
+}
+
+func TestUserInfoNonGitHubProviderUsesUserInfoFields(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"sub": "abc", "email": "user@example.com", "email_verified": true, "name": "Bob"}`))
+	}))
+	defer srv.Close()
+
+	provider := NewGenericProvider("oidc", config.OAuthProviderConfig{}, Endpoints{
+		UserInfoURL: srv.URL,
+	}, nil, defaultUserInfoFields)
+
+	info, err := provider.UserInfo(context.Background(), &OAuthToken{AccessToken: "tok"})
+	if err != nil {
+		t.Fatalf("UserInfo: %v", err)
+	}
+	if info.Email != "user@example.com" || !info.EmailVerified {
+		t.Errorf("unexpected info: %+v", info)
+	}
+}
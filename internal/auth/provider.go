@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/yourname/api-gateway/internal/store"
+)
+
+// LoginProvider authenticates a username/password pair against a credential
+// source. store.Store satisfies this interface via AuthenticateUser, making
+// local password login just one of potentially several ways to obtain an
+// identity.
+type LoginProvider interface {
+	AuthenticateUser(ctx context.Context, email, password string) (*store.Identity, error)
+}
+
+// OAuthToken is the token set returned by an OAuthProvider after exchanging
+// an authorization code.
+type OAuthToken struct {
+	AccessToken  string
+	RefreshToken string
+	Expiry       time.Time
+}
+
+// OAuthUserInfo is the subset of upstream profile data the gateway needs to
+// provision or resolve a local identity.
+type OAuthUserInfo struct {
+	// Subject is the provider's stable, opaque identifier for the user
+	// (OIDC "sub", GitHub numeric "id", ...). Combined with the provider
+	// name it forms the user_identities lookup key.
+	Subject string
+	Email   string
+	// EmailVerified reports whether the provider itself attests that Email
+	// is confirmed to belong to this subject (OIDC's "email_verified"
+	// claim). It gates auto-linking to an existing local account by email
+	// in Store.FindOrCreateOAuthIdentity: a provider that lets a user set
+	// an arbitrary, unverified profile email must never be allowed to take
+	// over an account it doesn't actually own.
+	EmailVerified bool
+	Name          string
+}
+
+// OAuthProvider is implemented by external identity providers reachable via
+// the OAuth2 authorization-code flow (Keycloak, GitHub, Google, or a generic
+// OIDC issuer).
+type OAuthProvider interface {
+	// Name is the short identifier used in the /auth/{provider}/... routes.
+	Name() string
+	// AuthCodeURL builds the redirect URL that starts the flow, embedding
+	// state for CSRF protection.
+	AuthCodeURL(state string) string
+	// Exchange swaps an authorization code for a token set.
+	Exchange(ctx context.Context, code string) (*OAuthToken, error)
+	// UserInfo fetches the authenticated user's profile using the token set.
+	UserInfo(ctx context.Context, token *OAuthToken) (*OAuthUserInfo, error)
+}
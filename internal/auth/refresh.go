@@ -0,0 +1,130 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yourname/api-gateway/internal/store"
+)
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
+// issueTokens generates a fresh access+refresh pair for identity. When
+// replaces is non-nil, the new refresh token rotates out the token with
+// that id (see store.CreateRefreshToken).
+func (h *Handler) issueTokens(ctx context.Context, identity *store.Identity, replaces *uuid.UUID) (loginResponse, error) {
+	accessToken, accessExp, err := h.generateToken(identity)
+	if err != nil {
+		return loginResponse{}, err
+	}
+
+	refreshToken, refreshHash, err := generateRefreshToken()
+	if err != nil {
+		return loginResponse{}, err
+	}
+	refreshExp := time.Now().Add(h.refreshTTL)
+
+	if _, err := h.store.CreateRefreshToken(ctx, identity.UserID, refreshHash, refreshExp, replaces); err != nil {
+		return loginResponse{}, err
+	}
+
+	return loginResponse{
+		Token:            accessToken,
+		ExpiresAt:        accessExp,
+		RefreshToken:     refreshToken,
+		RefreshExpiresAt: refreshExp,
+	}, nil
+}
+
+// generateRefreshToken returns a random opaque token plus the SHA-256 hash
+// that gets persisted; only the hash is stored, so a database leak doesn't
+// expose usable tokens.
+func generateRefreshToken() (token, hash string, err error) {
+	raw := make([]byte, 32)
+	if _, err := rand.Read(raw); err != nil {
+		return "", "", err
+	}
+	token = base64.RawURLEncoding.EncodeToString(raw)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshHandler returns an http.Handler for POST /auth/refresh. It
+// consumes the presented refresh token, rotating it for a fresh
+// access+refresh pair. Replaying an already-consumed token revokes the
+// user's entire refresh token chain.
+func (h *Handler) RefreshHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req refreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		identity, oldID, err := h.store.ConsumeRefreshToken(r.Context(), hashRefreshToken(req.RefreshToken))
+		if err != nil {
+			if errors.Is(err, store.ErrRefreshTokenReused) {
+				http.Error(w, "refresh token reuse detected, all sessions revoked", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "invalid refresh token", http.StatusUnauthorized)
+			return
+		}
+
+		resp, err := h.issueTokens(r.Context(), identity, &oldID)
+		if err != nil {
+			http.Error(w, "could not issue token", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	})
+}
+
+// LogoutHandler returns an http.Handler for POST /auth/logout. It revokes
+// the presented refresh token; it is not an error to log out a token that
+// is already revoked or unknown.
+func (h *Handler) LogoutHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		var req refreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.RefreshToken == "" {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+
+		if err := h.store.RevokeRefreshToken(r.Context(), hashRefreshToken(req.RefreshToken)); err != nil {
+			http.Error(w, "could not revoke token", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	})
+}
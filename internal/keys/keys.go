@@ -0,0 +1,248 @@
+// Package keys manages the gateway's JWT signing keys: an Ed25519 key pair
+// per kid, persisted to disk, with one key designated "current" for
+// signing while all known keys (including retired ones) remain valid for
+// verification.
+package keys
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	jose "github.com/go-jose/go-jose/v4"
+)
+
+// Record describes one key in the manifest.
+type Record struct {
+	KID       string    `json:"kid"`
+	File      string    `json:"file"`
+	Retired   bool      `json:"retired"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type manifest struct {
+	Current string   `json:"current"`
+	Keys    []Record `json:"keys"`
+}
+
+// KeySet holds every key the gateway knows about, loaded from (or
+// generated into) a directory on disk. It is safe for concurrent use.
+type KeySet struct {
+	mu      sync.RWMutex
+	dir     string
+	current string
+	private map[string]ed25519.PrivateKey
+	public  map[string]ed25519.PublicKey
+	records []Record
+}
+
+// Load reads dir's key manifest, generating and persisting a fresh Ed25519
+// key pair on first boot if no manifest exists yet.
+func Load(dir string) (*KeySet, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, err
+	}
+
+	ks := &KeySet{
+		dir:     dir,
+		private: make(map[string]ed25519.PrivateKey),
+		public:  make(map[string]ed25519.PublicKey),
+	}
+
+	manifestPath := filepath.Join(dir, "manifest.json")
+	m, err := readManifest(manifestPath)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		kid, err := ks.generateKey()
+		if err != nil {
+			return nil, err
+		}
+		m = manifest{Current: kid, Keys: []Record{{KID: kid, File: kid + ".pem", CreatedAt: time.Now()}}}
+		if err := writeManifest(manifestPath, m); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		for _, rec := range m.Keys {
+			priv, err := loadPrivateKey(filepath.Join(dir, rec.File))
+			if err != nil {
+				return nil, fmt.Errorf("keys: load %s: %w", rec.KID, err)
+			}
+			ks.private[rec.KID] = priv
+			ks.public[rec.KID] = priv.Public().(ed25519.PublicKey)
+		}
+	}
+
+	ks.current = m.Current
+	ks.records = m.Keys
+	return ks, nil
+}
+
+// Signer returns a jose.Signer for the current signing key, with its kid
+// embedded in the JWS header so VerifyToken callers can find the matching
+// public key later.
+func (ks *KeySet) Signer() (jose.Signer, error) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	priv, ok := ks.private[ks.current]
+	if !ok {
+		return nil, fmt.Errorf("keys: no current signing key")
+	}
+
+	jwk := jose.JSONWebKey{Key: priv, KeyID: ks.current, Algorithm: string(jose.EdDSA), Use: "sig"}
+	return jose.NewSigner(jose.SigningKey{Algorithm: jose.EdDSA, Key: jwk}, nil)
+}
+
+// PublicKey resolves kid to its public key, including retired keys, so
+// tokens signed before a rotation keep verifying until they expire.
+func (ks *KeySet) PublicKey(kid string) (ed25519.PublicKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	pub, ok := ks.public[kid]
+	return pub, ok
+}
+
+// JWKS renders every known key (current and retired) as a standard JWKS
+// document for GET /.well-known/jwks.json.
+func (ks *KeySet) JWKS() jose.JSONWebKeySet {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	set := jose.JSONWebKeySet{Keys: make([]jose.JSONWebKey, 0, len(ks.records))}
+	for _, rec := range ks.records {
+		set.Keys = append(set.Keys, jose.JSONWebKey{
+			Key:       ks.public[rec.KID],
+			KeyID:     rec.KID,
+			Algorithm: string(jose.EdDSA),
+			Use:       "sig",
+		})
+	}
+	return set
+}
+
+// AddKey generates a new key pair, persists it, and makes it the current
+// signing key. The previous current key keeps verifying but is no longer
+// signed with.
+func (ks *KeySet) AddKey() (string, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	kid, err := ks.generateKey()
+	if err != nil {
+		return "", err
+	}
+
+	ks.records = append(ks.records, Record{KID: kid, File: kid + ".pem", CreatedAt: time.Now()})
+	ks.current = kid
+
+	if err := writeManifest(filepath.Join(ks.dir, "manifest.json"), manifest{Current: ks.current, Keys: ks.records}); err != nil {
+		return "", err
+	}
+	return kid, nil
+}
+
+// RetireKey marks kid as retired: purely bookkeeping for operators, since
+// retired keys remain valid for verification. It refuses to retire the
+// active signing key; call AddKey first.
+func (ks *KeySet) RetireKey(kid string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	if kid == ks.current {
+		return fmt.Errorf("keys: cannot retire the active signing key %q, add a new key first", kid)
+	}
+
+	found := false
+	for i := range ks.records {
+		if ks.records[i].KID == kid {
+			ks.records[i].Retired = true
+			found = true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("keys: unknown key %q", kid)
+	}
+
+	return writeManifest(filepath.Join(ks.dir, "manifest.json"), manifest{Current: ks.current, Keys: ks.records})
+}
+
+// generateKey creates a new Ed25519 key pair, writes its private key to
+// disk as PKCS8 PEM, and registers it in the in-memory key maps. Callers
+// must hold ks.mu.
+func (ks *KeySet) generateKey() (string, error) {
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return "", err
+	}
+
+	kidBytes := make([]byte, 8)
+	if _, err := rand.Read(kidBytes); err != nil {
+		return "", err
+	}
+	kid := hex.EncodeToString(kidBytes)
+
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		return "", err
+	}
+	block := &pem.Block{Type: "PRIVATE KEY", Bytes: der}
+	if err := os.WriteFile(filepath.Join(ks.dir, kid+".pem"), pem.EncodeToMemory(block), 0o600); err != nil {
+		return "", err
+	}
+
+	ks.private[kid] = priv
+	ks.public[kid] = pub
+	return kid, nil
+}
+
+func loadPrivateKey(path string) (ed25519.PrivateKey, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(raw)
+	if block == nil {
+		return nil, fmt.Errorf("keys: %s is not valid PEM", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	priv, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("keys: %s is not an Ed25519 key", path)
+	}
+	return priv, nil
+}
+
+func readManifest(path string) (manifest, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return manifest{}, err
+	}
+	var m manifest
+	if err := json.Unmarshal(raw, &m); err != nil {
+		return manifest{}, err
+	}
+	return m, nil
+}
+
+func writeManifest(path string, m manifest) error {
+	raw, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0o600)
+}
@@ -11,7 +11,7 @@ import (
 // AuthConfig defines how the auth middleware behaves.
 type AuthConfig struct {
 	Store        *store.Store
-	JWTSecret    []byte
+	KeySet       auth.KeySet
 	APIKeyHeader string
 	SkipPaths    map[string]struct{}
 }
@@ -25,13 +25,13 @@ func Auth(cfg AuthConfig) func(http.Handler) http.Handler {
 				return
 			}
 
-			if identity, ok := authenticateJWT(r, cfg.JWTSecret); ok {
+			if identity, ok := AuthenticateJWT(r, cfg.KeySet); ok {
 				ctx := auth.WithIdentity(r.Context(), *identity)
 				next.ServeHTTP(w, r.WithContext(ctx))
 				return
 			}
 
-			if identity, ok := authenticateAPIKey(r, cfg); ok {
+			if identity, ok := AuthenticateAPIKey(r, cfg); ok {
 				ctx := auth.WithIdentity(r.Context(), identity)
 				next.ServeHTTP(w, r.WithContext(ctx))
 				return
@@ -42,7 +42,11 @@ func Auth(cfg AuthConfig) func(http.Handler) http.Handler {
 	}
 }
 
-func authenticateJWT(r *http.Request, secret []byte) (*auth.Identity, bool) {
+// AuthenticateJWT extracts and verifies a bearer token from r, returning the
+// identity it encodes. Exported so callers that enforce auth per-route
+// (e.g. the dynamic proxy router) can reuse this without going through the
+// full Auth chain.
+func AuthenticateJWT(r *http.Request, keySet auth.KeySet) (*auth.Identity, bool) {
 	authz := r.Header.Get("Authorization")
 	if authz == "" {
 		return nil, false
@@ -54,14 +58,30 @@ func authenticateJWT(r *http.Request, secret []byte) (*auth.Identity, bool) {
 	if token == "" {
 		return nil, false
 	}
-	identity, err := auth.VerifyToken(token, secret)
+	identity, err := auth.VerifyToken(token, keySet)
 	if err != nil {
 		return nil, false
 	}
 	return identity, true
 }
 
-func authenticateAPIKey(r *http.Request, cfg AuthConfig) (auth.Identity, bool) {
+// RequireScope wraps next so requests whose identity (set by Auth) lacks
+// the given scope are rejected with 403, before next ever runs.
+func RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !auth.HasScope(r.Context(), scope) {
+				http.Error(w, "forbidden", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// AuthenticateAPIKey looks up the caller's identity from an API key header,
+// exported for the same reason as AuthenticateJWT.
+func AuthenticateAPIKey(r *http.Request, cfg AuthConfig) (auth.Identity, bool) {
 	header := cfg.APIKeyHeader
 	if header == "" {
 		header = "X-API-Key"
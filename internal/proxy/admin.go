@@ -0,0 +1,16 @@
+package proxy
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// RoutesHandler returns an http.Handler for GET /admin/routes, exposing the
+// currently active route definitions. Callers should mount it behind
+// middleware.RequireScope("admin:routes").
+func (r *Router) RoutesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(r.Routes())
+	})
+}
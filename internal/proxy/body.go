@@ -0,0 +1,38 @@
+package proxy
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"os"
+	"time"
+)
+
+// statFile returns the modification time of path, used by Router.Watch to
+// detect changes without depending on an fsnotify-style library.
+func statFile(path string) (time.Time, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return info.ModTime(), nil
+}
+
+// readAndRestoreBody drains req.Body once and returns a factory that
+// produces a fresh io.ReadCloser over the same bytes, so proxyWithRetry can
+// replay the body across multiple upstream attempts.
+func readAndRestoreBody(req *http.Request) (func() io.ReadCloser, error) {
+	if req.Body == nil {
+		return func() io.ReadCloser { return nil }, nil
+	}
+
+	data, err := io.ReadAll(req.Body)
+	if err != nil {
+		return nil, err
+	}
+	_ = req.Body.Close()
+
+	return func() io.ReadCloser {
+		return io.NopCloser(bytes.NewReader(data))
+	}, nil
+}
@@ -0,0 +1,100 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Upstream is one weighted backend a route can be load-balanced across.
+type Upstream struct {
+	URL    string `yaml:"url" json:"url"`
+	Weight int    `yaml:"weight" json:"weight"`
+}
+
+// RetryPolicy controls how a route's reverse proxy reacts to a failed
+// upstream request.
+type RetryPolicy struct {
+	MaxRetries int           `yaml:"max_retries" json:"max_retries"`
+	Backoff    time.Duration `yaml:"backoff" json:"backoff"`
+}
+
+// RateLimit caps requests per caller identity for a route.
+type RateLimit struct {
+	RequestsPerSecond float64 `yaml:"requests_per_second" json:"requests_per_second"`
+	Burst             int     `yaml:"burst" json:"burst"`
+}
+
+// RouteDef describes one proxied route as read from config.
+type RouteDef struct {
+	Prefix    string        `yaml:"prefix" json:"prefix"`
+	Methods   []string      `yaml:"methods" json:"methods"`
+	Upstreams []Upstream    `yaml:"upstreams" json:"upstreams"`
+	Auth      string        `yaml:"auth" json:"auth"` // "jwt", "api_key", or "none"
+	Scopes    []string      `yaml:"scopes" json:"scopes"`
+	Timeout   time.Duration `yaml:"timeout" json:"timeout"`
+	Retry     RetryPolicy   `yaml:"retry" json:"retry"`
+	RateLimit *RateLimit    `yaml:"rate_limit" json:"rate_limit"`
+}
+
+type routeFile struct {
+	Routes []RouteDef `yaml:"routes" json:"routes"`
+}
+
+// LoadRoutes reads route definitions from a YAML or JSON file, selected by
+// its extension.
+func LoadRoutes(path string) ([]RouteDef, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("proxy: read routes file: %w", err)
+	}
+
+	var doc routeFile
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		err = json.Unmarshal(raw, &doc)
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(raw, &doc)
+	default:
+		return nil, fmt.Errorf("proxy: unsupported routes file extension %q", ext)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("proxy: parse routes file: %w", err)
+	}
+
+	for i := range doc.Routes {
+		if err := doc.Routes[i].validate(); err != nil {
+			return nil, fmt.Errorf("proxy: route %d: %w", i, err)
+		}
+	}
+
+	return doc.Routes, nil
+}
+
+func (r RouteDef) validate() error {
+	if r.Prefix == "" {
+		return fmt.Errorf("prefix is required")
+	}
+	if len(r.Upstreams) == 0 {
+		return fmt.Errorf("route %q needs at least one upstream", r.Prefix)
+	}
+	switch r.Auth {
+	case "", "none", "jwt", "api_key":
+	default:
+		return fmt.Errorf("route %q: unknown auth method %q", r.Prefix, r.Auth)
+	}
+	for _, up := range r.Upstreams {
+		if up.URL == "" {
+			return fmt.Errorf("route %q: upstream is missing a url", r.Prefix)
+		}
+	}
+	if r.Retry.MaxRetries < 0 {
+		return fmt.Errorf("route %q: retry.max_retries cannot be negative", r.Prefix)
+	}
+	return nil
+}
@@ -1,22 +1,6 @@
+// Package proxy implements the gateway's reverse-proxying layer: a
+// config-driven Router that dispatches requests to weighted upstream
+// backends per route, enforcing each route's auth method, scopes, and rate
+// limit. See router.go for the Router type and config.go for the route
+// definition format.
 package proxy
-
-import (
-	"net/http"
-	"net/http/httputil"
-	"net/url"
-	"strings"
-)
-
-func PathPrefixProxy(prefix, target string) http.Handler {
-	targetURL, err := url.Parse(target)
-	if err != nil {
-		panic(err)
-	}
-
-	proxy := httputil.NewSingleHostReverseProxy(targetURL)
-
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		r.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
-		proxy.ServeHTTP(w, r)
-	})
-}
@@ -0,0 +1,42 @@
+package proxy
+
+import (
+	"sync"
+
+	"golang.org/x/time/rate"
+)
+
+// limiterSet hands out one token bucket per caller identity for a single
+// route, so one user exceeding the limit doesn't throttle everyone else.
+type limiterSet struct {
+	rps   rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newLimiterSet(requestsPerSecond float64, burst int) *limiterSet {
+	if burst <= 0 {
+		burst = 1
+	}
+	return &limiterSet{
+		rps:      rate.Limit(requestsPerSecond),
+		burst:    burst,
+		limiters: make(map[string]*rate.Limiter),
+	}
+}
+
+// Allow reports whether a request from key is within its rate limit,
+// creating a new bucket for keys not seen before.
+func (s *limiterSet) Allow(key string) bool {
+	s.mu.Lock()
+	limiter, ok := s.limiters[key]
+	if !ok {
+		limiter = rate.NewLimiter(s.rps, s.burst)
+		s.limiters[key] = limiter
+	}
+	s.mu.Unlock()
+
+	return limiter.Allow()
+}
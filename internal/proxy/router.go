@@ -0,0 +1,341 @@
+package proxy
+
+import (
+	"context"
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httputil"
+	"net/url"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourname/api-gateway/internal/auth"
+	"github.com/yourname/api-gateway/internal/middleware"
+	"github.com/yourname/api-gateway/internal/store"
+)
+
+// Dependencies are the shared services route handlers need to authenticate
+// and authorize a request before it reaches the reverse proxy.
+type Dependencies struct {
+	Store        *store.Store
+	KeySet       auth.KeySet
+	APIKeyHeader string
+}
+
+// Router dispatches requests to the proxied route whose prefix matches,
+// enforcing that route's auth method, scopes, and rate limit before
+// invoking its load-balanced reverse proxy. Routes are loaded from a
+// config file and can be hot-reloaded via Watch.
+type Router struct {
+	deps Dependencies
+	path string
+
+	routes atomic.Pointer[[]*compiledRoute]
+}
+
+type compiledRoute struct {
+	def      RouteDef
+	prefix   string
+	methods  map[string]struct{}
+	upstream *weightedUpstreams
+	limiters *limiterSet
+}
+
+// NewRouter loads routes from path and compiles them immediately; call
+// Watch to keep them in sync with the file afterward.
+func NewRouter(path string, deps Dependencies) (*Router, error) {
+	r := &Router{deps: deps, path: path}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Reload re-reads and recompiles routes from disk, atomically swapping
+// them in. Existing in-flight requests keep using the routes they started
+// with.
+func (r *Router) Reload() error {
+	defs, err := LoadRoutes(r.path)
+	if err != nil {
+		return err
+	}
+
+	compiled := make([]*compiledRoute, 0, len(defs))
+	for _, def := range defs {
+		cr, err := compileRoute(def)
+		if err != nil {
+			return err
+		}
+		compiled = append(compiled, cr)
+	}
+
+	// Longest prefix first so the most specific route wins.
+	sort.Slice(compiled, func(i, j int) bool {
+		return len(compiled[i].prefix) > len(compiled[j].prefix)
+	})
+
+	r.routes.Store(&compiled)
+	return nil
+}
+
+// Watch polls the routes file every interval and reloads it on change,
+// logging (but not failing on) reload errors so a bad edit doesn't take
+// the gateway down. It returns immediately; the polling runs in a
+// background goroutine that stops once ctx is done.
+func (r *Router) Watch(ctx context.Context, interval time.Duration) {
+	go func() {
+		var lastMod time.Time
+		if info, err := statFile(r.path); err == nil {
+			lastMod = info
+		}
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				info, err := statFile(r.path)
+				if err != nil || !info.After(lastMod) {
+					continue
+				}
+				lastMod = info
+				if err := r.Reload(); err != nil {
+					log.Printf("proxy: routes reload failed: %v", err)
+					continue
+				}
+				log.Printf("proxy: routes reloaded from %s", r.path)
+			}
+		}
+	}()
+}
+
+// Routes returns the currently active route definitions, e.g. for an
+// admin inspection endpoint.
+func (r *Router) Routes() []RouteDef {
+	current := *r.routes.Load()
+	defs := make([]RouteDef, len(current))
+	for i, cr := range current {
+		defs[i] = cr.def
+	}
+	return defs
+}
+
+func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	cr := r.match(req)
+	if cr == nil {
+		http.NotFound(w, req)
+		return
+	}
+
+	if len(cr.methods) > 0 {
+		if _, ok := cr.methods[req.Method]; !ok {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+	}
+
+	identity, ok := r.authenticate(req, cr.def.Auth)
+	if !ok {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if identity != nil {
+		req = req.WithContext(auth.WithIdentity(req.Context(), *identity))
+	}
+
+	for _, scope := range cr.def.Scopes {
+		if !auth.HasScope(req.Context(), scope) {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+	}
+
+	if cr.limiters != nil {
+		key := "anonymous"
+		if identity != nil {
+			key = identity.UserID.String()
+		}
+		if !cr.limiters.Allow(key) {
+			http.Error(w, "rate limit exceeded", http.StatusTooManyRequests)
+			return
+		}
+	}
+
+	req.URL.Path = strings.TrimPrefix(req.URL.Path, cr.prefix)
+	proxyWithRetry(cr, w, req)
+}
+
+func (r *Router) match(req *http.Request) *compiledRoute {
+	for _, cr := range *r.routes.Load() {
+		if strings.HasPrefix(req.URL.Path, cr.prefix) {
+			return cr
+		}
+	}
+	return nil
+}
+
+// authenticate resolves the caller's identity per the route's declared
+// auth method. It returns (nil, true) for "none"/unset, since no identity
+// is required.
+func (r *Router) authenticate(req *http.Request, method string) (*auth.Identity, bool) {
+	switch method {
+	case "jwt":
+		return middleware.AuthenticateJWT(req, r.deps.KeySet)
+	case "api_key":
+		identity, ok := middleware.AuthenticateAPIKey(req, middleware.AuthConfig{
+			Store:        r.deps.Store,
+			APIKeyHeader: r.deps.APIKeyHeader,
+		})
+		return &identity, ok
+	default:
+		return nil, true
+	}
+}
+
+// weightedUpstreams picks an upstream reverse proxy weighted by its
+// configured Weight, falling back to equal weight when none are set.
+type weightedUpstreams struct {
+	total     int
+	proxies   []*httputil.ReverseProxy
+	cumWeight []int
+	targets   []string
+}
+
+func newWeightedUpstreams(upstreams []Upstream) (*weightedUpstreams, error) {
+	wu := &weightedUpstreams{}
+	cum := 0
+	for _, up := range upstreams {
+		targetURL, err := url.Parse(up.URL)
+		if err != nil {
+			return nil, err
+		}
+		weight := up.Weight
+		if weight <= 0 {
+			weight = 1
+		}
+		cum += weight
+		wu.proxies = append(wu.proxies, httputil.NewSingleHostReverseProxy(targetURL))
+		wu.cumWeight = append(wu.cumWeight, cum)
+		wu.targets = append(wu.targets, up.URL)
+	}
+	wu.total = cum
+	return wu, nil
+}
+
+// pick returns a weighted-random upstream index, skipping any in exclude.
+func (wu *weightedUpstreams) pick(exclude map[int]struct{}) int {
+	if len(exclude) >= len(wu.proxies) {
+		return -1
+	}
+	n := rand.Intn(wu.total) + 1
+	for i, cum := range wu.cumWeight {
+		if _, skip := exclude[i]; skip {
+			continue
+		}
+		if n <= cum {
+			return i
+		}
+	}
+	for i := range wu.proxies {
+		if _, skip := exclude[i]; !skip {
+			return i
+		}
+	}
+	return -1
+}
+
+func compileRoute(def RouteDef) (*compiledRoute, error) {
+	upstream, err := newWeightedUpstreams(def.Upstreams)
+	if err != nil {
+		return nil, err
+	}
+
+	methods := make(map[string]struct{}, len(def.Methods))
+	for _, m := range def.Methods {
+		methods[strings.ToUpper(m)] = struct{}{}
+	}
+
+	var limiters *limiterSet
+	if def.RateLimit != nil {
+		limiters = newLimiterSet(def.RateLimit.RequestsPerSecond, def.RateLimit.Burst)
+	}
+
+	return &compiledRoute{
+		def:      def,
+		prefix:   def.Prefix,
+		methods:  methods,
+		upstream: upstream,
+		limiters: limiters,
+	}, nil
+}
+
+// proxyWithRetry forwards req to one of the route's upstreams, retrying on
+// a failed attempt (connection error or 5xx) against a different upstream
+// up to the route's configured MaxRetries. Each attempt is buffered into its
+// own recorder rather than writing straight to w: a failed attempt may have
+// already sent a status line and partial body by the time the retry
+// decision is made, and replaying that onto the real connection would
+// corrupt the response the client eventually sees.
+func proxyWithRetry(cr *compiledRoute, w http.ResponseWriter, req *http.Request) {
+	attempts := cr.def.Retry.MaxRetries + 1
+	if attempts < 1 {
+		attempts = 1
+	}
+	tried := make(map[int]struct{}, attempts)
+
+	body, err := readAndRestoreBody(req)
+	if err != nil {
+		http.Error(w, "could not read request body", http.StatusInternalServerError)
+		return
+	}
+
+	var rec *httptest.ResponseRecorder
+	for attempt := 0; attempt < attempts; attempt++ {
+		idx := cr.upstream.pick(tried)
+		if idx < 0 {
+			http.Error(w, "no upstream available", http.StatusBadGateway)
+			return
+		}
+		tried[idx] = struct{}{}
+
+		attemptReq := req.Clone(req.Context())
+		attemptReq.Body = body()
+		if cr.def.Timeout > 0 {
+			ctx, cancel := context.WithTimeout(req.Context(), cr.def.Timeout)
+			defer cancel()
+			attemptReq = attemptReq.WithContext(ctx)
+		}
+
+		rec = httptest.NewRecorder()
+		cr.upstream.proxies[idx].ServeHTTP(rec, attemptReq)
+
+		if rec.Code < http.StatusInternalServerError {
+			break
+		}
+		if attempt < attempts-1 && cr.def.Retry.Backoff > 0 {
+			time.Sleep(cr.def.Retry.Backoff)
+		}
+	}
+
+	flushRecorder(w, rec)
+}
+
+// flushRecorder copies a buffered upstream attempt's headers, status, and
+// body onto the real client connection. Called once, with the winning (or
+// final) attempt, after the retry loop has decided no further attempt will
+// be made.
+func flushRecorder(w http.ResponseWriter, rec *httptest.ResponseRecorder) {
+	dst := w.Header()
+	for k, vs := range rec.Header() {
+		dst[k] = vs
+	}
+	w.WriteHeader(rec.Code)
+	w.Write(rec.Body.Bytes())
+}
@@ -0,0 +1,102 @@
+package proxy
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestProxyWithRetryDoesNotDoubleWrite guards against a failed first
+// attempt's status/body leaking onto the real response before the retry
+// loop falls back to a working upstream.
+func TestProxyWithRetryDoesNotDoubleWrite(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+		w.Write([]byte("upstream down"))
+	}))
+	defer failing.Close()
+
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("ok"))
+	}))
+	defer ok.Close()
+
+	def := RouteDef{
+		Prefix:    "/",
+		Upstreams: []Upstream{{URL: failing.URL}, {URL: ok.URL}},
+		Retry:     RetryPolicy{MaxRetries: 1},
+	}
+	cr, err := compileRoute(def)
+	if err != nil {
+		t.Fatalf("compileRoute: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	proxyWithRetry(cr, w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected the retry to land on the healthy upstream with 200, got %d body=%q", w.Code, w.Body.String())
+	}
+	if w.Body.String() != "ok" {
+		t.Fatalf("expected only the winning attempt's body, got %q", w.Body.String())
+	}
+}
+
+func TestProxyWithRetryExhaustsUpstreams(t *testing.T) {
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+
+	def := RouteDef{
+		Prefix:    "/",
+		Upstreams: []Upstream{{URL: failing.URL}},
+		Retry:     RetryPolicy{MaxRetries: 0},
+	}
+	cr, err := compileRoute(def)
+	if err != nil {
+		t.Fatalf("compileRoute: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	proxyWithRetry(cr, w, req)
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected the failing upstream's own status to be surfaced, got %d", w.Code)
+	}
+}
+
+// TestProxyWithRetryNegativeMaxRetries guards against a compiledRoute built
+// directly (bypassing RouteDef.validate's max_retries check) with a
+// negative MaxRetries, which would make the attempts counter <= 0 and skip
+// the loop body entirely.
+func TestProxyWithRetryNegativeMaxRetries(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer upstream.Close()
+
+	def := RouteDef{
+		Prefix:    "/",
+		Upstreams: []Upstream{{URL: upstream.URL}},
+		Retry:     RetryPolicy{MaxRetries: -1},
+	}
+	cr, err := compileRoute(def)
+	if err != nil {
+		t.Fatalf("compileRoute: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+
+	proxyWithRetry(cr, w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("expected at least one attempt to run, got %d", w.Code)
+	}
+}
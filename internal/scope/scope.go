@@ -0,0 +1,69 @@
+// Package scope defines the verb+resource access scopes carried by gateway
+// tokens (JWTs and API keys) and used to authorize individual routes.
+package scope
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Scope is a single permission grant: a verb (read, write, admin, ...)
+// paired with a resource pattern (service-a/*, service-a/widgets, ...).
+type Scope struct {
+	Verb     string
+	Resource string
+}
+
+// Parse reads a scope in its wire form "verb:resource", e.g.
+// "read:service-a/*" or "admin:*".
+func Parse(raw string) (Scope, error) {
+	verb, resource, ok := strings.Cut(raw, ":")
+	if !ok || verb == "" || resource == "" {
+		return Scope{}, fmt.Errorf("scope: invalid scope %q, want \"verb:resource\"", raw)
+	}
+	return Scope{Verb: verb, Resource: resource}, nil
+}
+
+// String renders the scope back to its wire form.
+func (s Scope) String() string {
+	return s.Verb + ":" + s.Resource
+}
+
+// Matches reports whether s grants the required scope: verbs must match
+// exactly (an "admin" verb does not implicitly satisfy "read"), and s's
+// resource pattern must match required's resource. A bare "*" resource
+// matches anything, including multi-segment resources like
+// "service-a/widgets" - this is what lets e.g. "admin:*" grant admin over
+// everything. Any other pattern matches via path.Match-style globbing,
+// e.g. "service-a/*" matches "service-a/widgets" but not
+// "service-a/widgets/1", since path.Match's "*" does not cross "/".
+func (s Scope) Matches(required Scope) bool {
+	if s.Verb != required.Verb {
+		return false
+	}
+	if s.Resource == "*" {
+		return true
+	}
+	ok, err := path.Match(s.Resource, required.Resource)
+	return err == nil && ok
+}
+
+// Has reports whether any of the granted scopes (in their wire form)
+// satisfies the required scope. Malformed granted scopes are ignored.
+func Has(granted []string, required string) bool {
+	want, err := Parse(required)
+	if err != nil {
+		return false
+	}
+	for _, raw := range granted {
+		have, err := Parse(raw)
+		if err != nil {
+			continue
+		}
+		if have.Matches(want) {
+			return true
+		}
+	}
+	return false
+}
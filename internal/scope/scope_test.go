@@ -0,0 +1,54 @@
+package scope
+
+import "testing"
+
+func TestScopeMatches(t *testing.T) {
+	cases := []struct {
+		name     string
+		granted  string
+		required string
+		want     bool
+	}{
+		{"exact resource match", "read:service-a/widgets", "read:service-a/widgets", true},
+		{"verb mismatch", "admin:service-a/widgets", "read:service-a/widgets", false},
+		{"single-segment glob", "read:service-a/*", "read:service-a/widgets", true},
+		{"single-segment glob does not cross slash", "read:service-a/*", "read:service-a/widgets/1", false},
+		{"bare star matches single segment", "admin:*", "admin:service-a", true},
+		{"bare star matches multi-segment resource", "admin:*", "admin:service-a/widgets", true},
+		{"bare star does not satisfy a different verb", "admin:*", "read:service-a/widgets", false},
+		{"no match without wildcard", "read:service-a/widgets", "read:service-b/widgets", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			granted, err := Parse(tc.granted)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tc.granted, err)
+			}
+			required, err := Parse(tc.required)
+			if err != nil {
+				t.Fatalf("Parse(%q): %v", tc.required, err)
+			}
+			if got := granted.Matches(required); got != tc.want {
+				t.Errorf("%q.Matches(%q) = %v, want %v", tc.granted, tc.required, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestHas(t *testing.T) {
+	granted := []string{"read:service-a/*", "admin:*", "not-a-valid-scope"}
+
+	if !Has(granted, "admin:service-a/widgets") {
+		t.Error("expected admin:* to satisfy admin:service-a/widgets")
+	}
+	if !Has(granted, "read:service-a/widgets") {
+		t.Error("expected read:service-a/* to satisfy read:service-a/widgets")
+	}
+	if Has(granted, "write:service-a/widgets") {
+		t.Error("expected no granted scope to satisfy write:service-a/widgets")
+	}
+	if Has(granted, "missingcolon") {
+		t.Error("expected an unparsable required scope to never match")
+	}
+}
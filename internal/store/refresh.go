@@ -0,0 +1,156 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+)
+
+var (
+	// ErrRefreshTokenNotFound signals that the presented refresh token does
+	// not exist.
+	ErrRefreshTokenNotFound = errors.New("refresh token not found")
+	// ErrRefreshTokenReused signals that an already-consumed refresh token
+	// was presented again, which revokes the rest of the user's chain as a
+	// reuse-detection defense.
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+	// ErrRefreshTokenExpired signals that the refresh token's expiry has
+	// passed.
+	ErrRefreshTokenExpired = errors.New("refresh token expired")
+)
+
+// CreateRefreshToken persists a new refresh token for userID, hashed as
+// tokenHash. If replaces is non-nil, it is the id of the token being
+// rotated out; that row's replaced_by column is set to the new token's id
+// in the same transaction.
+func (s *Store) CreateRefreshToken(ctx context.Context, userID uuid.UUID, tokenHash string, expiresAt time.Time, replaces *uuid.UUID) (uuid.UUID, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	id := uuid.New()
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO refresh_tokens (id, user_id, token_hash, issued_at, expires_at) VALUES ($1, $2, $3, now(), $4)`,
+		id, userID, tokenHash, expiresAt,
+	); err != nil {
+		return uuid.Nil, err
+	}
+
+	if replaces != nil {
+		if _, err := tx.ExecContext(ctx,
+			`UPDATE refresh_tokens SET replaced_by = $1 WHERE id = $2`,
+			id, *replaces,
+		); err != nil {
+			return uuid.Nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return uuid.Nil, err
+	}
+	return id, nil
+}
+
+// ConsumeRefreshToken atomically marks the refresh token identified by
+// tokenHash as revoked and returns the identity it belonged to along with
+// the token's id (for CreateRefreshToken's replaces argument during
+// rotation). If the token was already revoked, it is being replayed: the
+// user's entire refresh token chain is revoked and ErrRefreshTokenReused is
+// returned.
+func (s *Store) ConsumeRefreshToken(ctx context.Context, tokenHash string) (*Identity, uuid.UUID, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, uuid.Nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		id        uuid.UUID
+		userID    uuid.UUID
+		expiresAt time.Time
+		revokedAt sql.NullTime
+	)
+	err = tx.QueryRowContext(ctx,
+		`SELECT id, user_id, expires_at, revoked_at FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`,
+		tokenHash,
+	).Scan(&id, &userID, &expiresAt, &revokedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, uuid.Nil, ErrRefreshTokenNotFound
+		}
+		return nil, uuid.Nil, err
+	}
+
+	if revokedAt.Valid {
+		if err := revokeAllForUserTx(ctx, tx, userID); err != nil {
+			return nil, uuid.Nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, uuid.Nil, err
+		}
+		return nil, uuid.Nil, ErrRefreshTokenReused
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET revoked_at = now() WHERE id = $1`, id); err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	if time.Now().After(expiresAt) {
+		if err := tx.Commit(); err != nil {
+			return nil, uuid.Nil, err
+		}
+		return nil, uuid.Nil, ErrRefreshTokenExpired
+	}
+
+	var (
+		email  string
+		scopes []string
+	)
+	if err := tx.QueryRowContext(ctx, `SELECT email, scopes FROM users WHERE id = $1`, userID).Scan(&email, pq.Array(&scopes)); err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, uuid.Nil, err
+	}
+
+	return &Identity{UserID: userID, Email: email, Scopes: scopes}, id, nil
+}
+
+// RevokeRefreshToken revokes a single refresh token, e.g. on logout. It is
+// a no-op if the token does not exist or is already revoked.
+func (s *Store) RevokeRefreshToken(ctx context.Context, tokenHash string) error {
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = now() WHERE token_hash = $1 AND revoked_at IS NULL`,
+		tokenHash,
+	)
+	return err
+}
+
+// RevokeAllForUser revokes every active refresh token belonging to userID.
+func (s *Store) RevokeAllForUser(ctx context.Context, userID uuid.UUID) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := revokeAllForUserTx(ctx, tx, userID); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func revokeAllForUserTx(ctx context.Context, tx *sql.Tx, userID uuid.UUID) error {
+	_, err := tx.ExecContext(ctx,
+		`UPDATE refresh_tokens SET revoked_at = now() WHERE user_id = $1 AND revoked_at IS NULL`,
+		userID,
+	)
+	return err
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func newMockStore(t *testing.T) (*Store, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("sqlmock.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return New(db), mock
+}
+
+func TestConsumeRefreshTokenSuccess(t *testing.T) {
+	s, mock := newMockStore(t)
+
+	tokenID := uuid.New()
+	userID := uuid.New()
+	hash := "hash-of-token"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, user_id, expires_at, revoked_at FROM refresh_tokens`).
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at"}).
+			AddRow(tokenID, userID, time.Now().Add(time.Hour), nil))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = now\(\) WHERE id = \$1`).
+		WithArgs(tokenID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectQuery(`SELECT email, scopes FROM users WHERE id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"email", "scopes"}).
+			AddRow("user@example.com", "{read:service-a}"))
+	mock.ExpectCommit()
+
+	identity, id, err := s.ConsumeRefreshToken(context.Background(), hash)
+	if err != nil {
+		t.Fatalf("ConsumeRefreshToken: %v", err)
+	}
+	if id != tokenID {
+		t.Errorf("expected returned id %v, got %v", tokenID, id)
+	}
+	if identity.UserID != userID || identity.Email != "user@example.com" {
+		t.Errorf("unexpected identity: %+v", identity)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestConsumeRefreshTokenReuseRevokesChain exercises the reuse-detection
+// path: presenting an already-revoked refresh token must revoke every other
+// active token for that user and report ErrRefreshTokenReused, not quietly
+// fail or let the presented token through.
+func TestConsumeRefreshTokenReuseRevokesChain(t *testing.T) {
+	s, mock := newMockStore(t)
+
+	tokenID := uuid.New()
+	userID := uuid.New()
+	hash := "hash-of-reused-token"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, user_id, expires_at, revoked_at FROM refresh_tokens`).
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at"}).
+			AddRow(tokenID, userID, time.Now().Add(time.Hour), time.Now().Add(-time.Minute)))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = now\(\) WHERE user_id = \$1 AND revoked_at IS NULL`).
+		WithArgs(userID).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	_, _, err := s.ConsumeRefreshToken(context.Background(), hash)
+	if !errors.Is(err, ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestConsumeRefreshTokenExpired(t *testing.T) {
+	s, mock := newMockStore(t)
+
+	tokenID := uuid.New()
+	userID := uuid.New()
+	hash := "hash-of-expired-token"
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, user_id, expires_at, revoked_at FROM refresh_tokens`).
+		WithArgs(hash).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "revoked_at"}).
+			AddRow(tokenID, userID, time.Now().Add(-time.Hour), nil))
+	mock.ExpectExec(`UPDATE refresh_tokens SET revoked_at = now\(\) WHERE id = \$1`).
+		WithArgs(tokenID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	_, _, err := s.ConsumeRefreshToken(context.Background(), hash)
+	if !errors.Is(err, ErrRefreshTokenExpired) {
+		t.Fatalf("expected ErrRefreshTokenExpired, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestConsumeRefreshTokenNotFound(t *testing.T) {
+	s, mock := newMockStore(t)
+
+	hash := "unknown-hash"
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, user_id, expires_at, revoked_at FROM refresh_tokens`).
+		WithArgs(hash).
+		WillReturnError(sql.ErrNoRows)
+
+	_, _, err := s.ConsumeRefreshToken(context.Background(), hash)
+	if !errors.Is(err, ErrRefreshTokenNotFound) {
+		t.Fatalf("expected ErrRefreshTokenNotFound, got %v", err)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"errors"
 
 	"github.com/google/uuid"
+	"github.com/lib/pq"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -14,12 +15,20 @@ var (
 	ErrInvalidCredentials = errors.New("invalid credentials")
 	// ErrAPIKeyNotFound signals that the provided API key is missing or revoked.
 	ErrAPIKeyNotFound = errors.New("api key not found")
+	// ErrIdentityNotFound signals that no local identity is linked to the
+	// given upstream (provider, subject) pair yet.
+	ErrIdentityNotFound = errors.New("identity not found")
+	// ErrEmailNotVerified signals that an upstream identity's email matches
+	// an existing local account, but the provider did not assert the email
+	// as verified, so FindOrCreateOAuthIdentity refused to auto-link them.
+	ErrEmailNotVerified = errors.New("oauth: provider did not assert the account email as verified; cannot auto-link to an existing account")
 )
 
 // Identity represents the authenticated user.
 type Identity struct {
 	UserID uuid.UUID
 	Email  string
+	Scopes []string
 }
 
 // Store wraps database operations for users and API keys.
@@ -34,15 +43,16 @@ func New(db *sql.DB) *Store {
 
 // AuthenticateUser verifies the supplied credentials.
 func (s *Store) AuthenticateUser(ctx context.Context, email, password string) (*Identity, error) {
-	const query = `SELECT id, email, password_hash FROM users WHERE email = $1 LIMIT 1`
+	const query = `SELECT id, email, password_hash, scopes FROM users WHERE email = $1 LIMIT 1`
 
 	var (
 		id           uuid.UUID
 		storedEmail  string
 		passwordHash string
+		scopes       []string
 	)
 
-	err := s.db.QueryRowContext(ctx, query, email).Scan(&id, &storedEmail, &passwordHash)
+	err := s.db.QueryRowContext(ctx, query, email).Scan(&id, &storedEmail, &passwordHash, pq.Array(&scopes))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrInvalidCredentials
@@ -54,13 +64,98 @@ func (s *Store) AuthenticateUser(ctx context.Context, email, password string) (*
 		return nil, ErrInvalidCredentials
 	}
 
-	return &Identity{UserID: id, Email: storedEmail}, nil
+	return &Identity{UserID: id, Email: storedEmail, Scopes: scopes}, nil
+}
+
+// FindOrCreateOAuthIdentity resolves the local identity linked to the given
+// (provider, subject) pair, provisioning a new user on first login. If no
+// link exists but a user already has a matching email, the upstream
+// identity is linked to that existing account instead of creating a
+// duplicate - but only when emailVerified is true. A provider that lets a
+// user claim an arbitrary, unverified profile email must never be allowed
+// to take over an existing account just by logging in with that email; in
+// that case this returns ErrEmailNotVerified instead of linking.
+func (s *Store) FindOrCreateOAuthIdentity(ctx context.Context, provider, subject, email string, emailVerified bool) (*Identity, error) {
+	identity, err := s.findIdentityByUpstream(ctx, provider, subject)
+	if err == nil {
+		return identity, nil
+	}
+	if !errors.Is(err, ErrIdentityNotFound) {
+		return nil, err
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	var (
+		userID uuid.UUID
+		scopes []string
+	)
+	err = tx.QueryRowContext(ctx, `SELECT id, scopes FROM users WHERE email = $1 LIMIT 1`, email).Scan(&userID, pq.Array(&scopes))
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		if err := tx.QueryRowContext(ctx,
+			`INSERT INTO users (id, email, password_hash) VALUES ($1, $2, '') RETURNING id, scopes`,
+			uuid.New(), email,
+		).Scan(&userID, pq.Array(&scopes)); err != nil {
+			return nil, err
+		}
+	case err != nil:
+		return nil, err
+	default:
+		if !emailVerified {
+			return nil, ErrEmailNotVerified
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx,
+		`INSERT INTO user_identities (provider, subject, user_id) VALUES ($1, $2, $3)`,
+		provider, subject, userID,
+	); err != nil {
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	return &Identity{UserID: userID, Email: email, Scopes: scopes}, nil
+}
+
+// findIdentityByUpstream looks up the local identity already linked to a
+// (provider, subject) pair. It returns ErrIdentityNotFound when no link
+// exists yet.
+func (s *Store) findIdentityByUpstream(ctx context.Context, provider, subject string) (*Identity, error) {
+	const query = `
+SELECT u.id, u.email, u.scopes
+FROM user_identities ui
+JOIN users u ON ui.user_id = u.id
+WHERE ui.provider = $1
+  AND ui.subject = $2
+LIMIT 1`
+
+	var (
+		id     uuid.UUID
+		email  string
+		scopes []string
+	)
+	err := s.db.QueryRowContext(ctx, query, provider, subject).Scan(&id, &email, pq.Array(&scopes))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrIdentityNotFound
+		}
+		return nil, err
+	}
+	return &Identity{UserID: id, Email: email, Scopes: scopes}, nil
 }
 
 // LookupAPIKey fetches a user identity by API key, ensuring the key is active.
 func (s *Store) LookupAPIKey(ctx context.Context, key string) (*Identity, error) {
 	const query = `
-SELECT ak.user_id, u.email
+SELECT ak.user_id, u.email, ak.scopes
 FROM api_keys ak
 JOIN users u ON ak.user_id = u.id
 WHERE ak.key = $1
@@ -68,15 +163,16 @@ WHERE ak.key = $1
 LIMIT 1`
 
 	var (
-		id    uuid.UUID
-		email string
+		id     uuid.UUID
+		email  string
+		scopes []string
 	)
-	err := s.db.QueryRowContext(ctx, query, key).Scan(&id, &email)
+	err := s.db.QueryRowContext(ctx, query, key).Scan(&id, &email, pq.Array(&scopes))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, ErrAPIKeyNotFound
 		}
 		return nil, err
 	}
-	return &Identity{UserID: id, Email: email}, nil
+	return &Identity{UserID: id, Email: email, Scopes: scopes}, nil
 }
@@ -0,0 +1,131 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/google/uuid"
+)
+
+func TestFindOrCreateOAuthIdentityExistingLink(t *testing.T) {
+	s, mock := newMockStore(t)
+
+	userID := uuid.New()
+	mock.ExpectQuery(`SELECT u.id, u.email, u.scopes`).
+		WithArgs("github", "subj-1").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "email", "scopes"}).
+			AddRow(userID, "user@example.com", "{read:service-a,admin:*}"))
+
+	identity, err := s.FindOrCreateOAuthIdentity(context.Background(), "github", "subj-1", "user@example.com", true)
+	if err != nil {
+		t.Fatalf("FindOrCreateOAuthIdentity: %v", err)
+	}
+	if identity.UserID != userID {
+		t.Errorf("expected user id %v, got %v", userID, identity.UserID)
+	}
+	if len(identity.Scopes) != 2 {
+		t.Errorf("expected the already-linked identity's scopes to come through, got %v", identity.Scopes)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestFindOrCreateOAuthIdentityProvisionsNewUser(t *testing.T) {
+	s, mock := newMockStore(t)
+
+	newUserID := uuid.New()
+
+	mock.ExpectQuery(`SELECT u.id, u.email, u.scopes`).
+		WithArgs("github", "subj-2").
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, scopes FROM users WHERE email = \$1`).
+		WithArgs("new@example.com").
+		WillReturnError(sql.ErrNoRows)
+	mock.ExpectQuery(`INSERT INTO users \(id, email, password_hash\) VALUES \(\$1, \$2, ''\) RETURNING id, scopes`).
+		WithArgs(sqlmock.AnyArg(), "new@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "scopes"}).AddRow(newUserID, "{}"))
+	mock.ExpectExec(`INSERT INTO user_identities \(provider, subject, user_id\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs("github", "subj-2", newUserID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	identity, err := s.FindOrCreateOAuthIdentity(context.Background(), "github", "subj-2", "new@example.com", false)
+	if err != nil {
+		t.Fatalf("FindOrCreateOAuthIdentity: %v", err)
+	}
+	if identity.UserID != newUserID || identity.Email != "new@example.com" {
+		t.Errorf("unexpected identity: %+v", identity)
+	}
+	if len(identity.Scopes) != 0 {
+		t.Errorf("expected a freshly provisioned user to start with no scopes, got %v", identity.Scopes)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+func TestFindOrCreateOAuthIdentityLinksVerifiedEmailToExistingUser(t *testing.T) {
+	s, mock := newMockStore(t)
+
+	existingUserID := uuid.New()
+
+	mock.ExpectQuery(`SELECT u.id, u.email, u.scopes`).
+		WithArgs("github", "subj-3").
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, scopes FROM users WHERE email = \$1`).
+		WithArgs("alice@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "scopes"}).AddRow(existingUserID, "{admin:*}"))
+	mock.ExpectExec(`INSERT INTO user_identities \(provider, subject, user_id\) VALUES \(\$1, \$2, \$3\)`).
+		WithArgs("github", "subj-3", existingUserID).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	identity, err := s.FindOrCreateOAuthIdentity(context.Background(), "github", "subj-3", "alice@example.com", true)
+	if err != nil {
+		t.Fatalf("FindOrCreateOAuthIdentity: %v", err)
+	}
+	if identity.UserID != existingUserID {
+		t.Errorf("expected the upstream identity to link to the existing user %v, got %v", existingUserID, identity.UserID)
+	}
+	if len(identity.Scopes) != 1 || identity.Scopes[0] != "admin:*" {
+		t.Errorf("expected the existing account's scopes to carry over, got %v", identity.Scopes)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}
+
+// TestFindOrCreateOAuthIdentityRejectsUnverifiedEmailLink is the security
+// regression test for the account-takeover path: a matching email must
+// never be auto-linked unless the provider asserts it's verified.
+func TestFindOrCreateOAuthIdentityRejectsUnverifiedEmailLink(t *testing.T) {
+	s, mock := newMockStore(t)
+
+	existingUserID := uuid.New()
+
+	mock.ExpectQuery(`SELECT u.id, u.email, u.scopes`).
+		WithArgs("generic-oidc", "subj-4").
+		WillReturnError(sql.ErrNoRows)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT id, scopes FROM users WHERE email = \$1`).
+		WithArgs("victim@example.com").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "scopes"}).AddRow(existingUserID, "{}"))
+	mock.ExpectRollback()
+
+	_, err := s.FindOrCreateOAuthIdentity(context.Background(), "generic-oidc", "subj-4", "victim@example.com", false)
+	if !errors.Is(err, ErrEmailNotVerified) {
+		t.Fatalf("expected ErrEmailNotVerified, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("unmet expectations: %v", err)
+	}
+}